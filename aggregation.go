@@ -0,0 +1,177 @@
+package statsd
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// aggShardCount is the number of shards the aggregator splits its keyspace
+// across, to keep the hot Count/Gauge/Unique paths from contending on a
+// single mutex.
+const aggShardCount = 32
+
+// Aggregation turns on client-side aggregation of Client.Count,
+// Client.Increment and Client.Gauge calls. Instead of writing through to the
+// connection on every call, values accumulate in memory and are flushed
+// every flushInterval: counters as their summed total, gauges as their
+// last-observed value and sets (Client.Unique) as one line per distinct
+// value seen.
+//
+// This option is ignored in Client.Clone(); clones share their parent's
+// aggregator instead.
+func Aggregation(flushInterval time.Duration) Option {
+	return Option(func(c *config) {
+		c.Client.AggInterval = flushInterval
+	})
+}
+
+// aggKey identifies an aggregated metric: its fully prefixed bucket name and
+// its already-formatted tag suffix.
+type aggKey struct {
+	metric string
+	tags   string
+}
+
+func (k aggKey) shard(n uint32) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(k.metric))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(k.tags))
+	return h.Sum32() % n
+}
+
+// aggShard holds one slice of the aggregator's keyspace behind its own
+// mutex, so unrelated keys never contend with each other.
+type aggShard struct {
+	mu       sync.Mutex
+	counters map[aggKey]float64
+	gauges   map[aggKey]interface{}
+	sets     map[aggKey]map[string]struct{}
+}
+
+func newAggShard() *aggShard {
+	return &aggShard{
+		counters: make(map[aggKey]float64),
+		gauges:   make(map[aggKey]interface{}),
+		sets:     make(map[aggKey]map[string]struct{}),
+	}
+}
+
+// drain atomically swaps in fresh maps and returns the ones that were
+// accumulated since the last drain.
+func (s *aggShard) drain() (counters map[aggKey]float64, gauges map[aggKey]interface{}, sets map[aggKey]map[string]struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters, gauges, sets = s.counters, s.gauges, s.sets
+	s.counters = make(map[aggKey]float64)
+	s.gauges = make(map[aggKey]interface{})
+	s.sets = make(map[aggKey]map[string]struct{})
+	return counters, gauges, sets
+}
+
+// metricAggregator accumulates counters, gauges and sets in memory and
+// periodically flushes them through a Client's sink as single lines per key.
+type metricAggregator struct {
+	c        *Client
+	shards   [aggShardCount]*aggShard
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newMetricAggregator(c *Client, flushInterval time.Duration) *metricAggregator {
+	a := &metricAggregator{c: c, done: make(chan struct{})}
+	for i := range a.shards {
+		a.shards[i] = newAggShard()
+	}
+	go a.loop(flushInterval)
+	return a
+}
+
+func (a *metricAggregator) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.flush()
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// stop flushes any remaining samples and shuts the aggregator down. It is
+// safe to call more than once.
+func (a *metricAggregator) stop() {
+	a.stopOnce.Do(func() {
+		close(a.done)
+		a.flush()
+	})
+}
+
+func (a *metricAggregator) shardFor(key aggKey) *aggShard {
+	return a.shards[key.shard(aggShardCount)]
+}
+
+func (a *metricAggregator) addCount(metric, tags string, n float64) {
+	s := a.shardFor(aggKey{metric, tags})
+	s.mu.Lock()
+	s.counters[aggKey{metric, tags}] += n
+	s.mu.Unlock()
+}
+
+func (a *metricAggregator) setGauge(metric, tags string, value interface{}) {
+	s := a.shardFor(aggKey{metric, tags})
+	s.mu.Lock()
+	s.gauges[aggKey{metric, tags}] = value
+	s.mu.Unlock()
+}
+
+func (a *metricAggregator) addUnique(metric, tags, value string) {
+	key := aggKey{metric, tags}
+	s := a.shardFor(key)
+	s.mu.Lock()
+	set, ok := s.sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		s.sets[key] = set
+	}
+	set[value] = struct{}{}
+	s.mu.Unlock()
+}
+
+// contextCount returns the number of distinct (bucket, tags) keys currently
+// held across every shard, for reporting as the
+// statsd.client.aggregated_context telemetry metric.
+func (a *metricAggregator) contextCount() int {
+	n := 0
+	for _, s := range a.shards {
+		s.mu.Lock()
+		n += len(s.counters) + len(s.gauges) + len(s.sets)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// flush drains every shard and writes the accumulated counters, gauges and
+// sets through the aggregator's Client sink.
+func (a *metricAggregator) flush() {
+	for _, s := range a.shards {
+		counters, gauges, sets := s.drain()
+
+		for k, total := range counters {
+			a.c.sink.WriteMetric("", k.metric, total, "c", 1, k.tags)
+		}
+		for k, v := range gauges {
+			a.c.sink.WriteMetric("", k.metric, v, "g", 1, k.tags)
+		}
+		for k, set := range sets {
+			for v := range set {
+				a.c.sink.WriteUnique("", k.metric, v, k.tags)
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package statsd
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestMetricAggregatorFlushSumsCountersAndKeepsLastGauge(t *testing.T) {
+	sink := NewMemorySink()
+	c := NewWithSink(sink, Aggregation(time.Hour))
+
+	c.Count("hits", 1)
+	c.Count("hits", 2)
+	c.Count("hits", 3)
+	c.Gauge("temp", 10)
+	c.Gauge("temp", 20)
+	c.Unique("users", "alice")
+	c.Unique("users", "bob")
+	c.Unique("users", "alice") // duplicate, should only flush once
+	c.Close()                  // stops the aggregator, forcing a final drain
+
+	var counts, gauges, uniques int
+	for _, m := range sink.Metrics {
+		switch m.Bucket {
+		case "hits":
+			counts++
+			if m.Value.(float64) != 6 {
+				t.Errorf("hits total = %v, want 6", m.Value)
+			}
+		case "temp":
+			gauges++
+			if m.Value != 20 {
+				t.Errorf("temp = %v, want 20 (last value)", m.Value)
+			}
+		}
+	}
+	for _, u := range sink.Uniques {
+		if u.Bucket == "users" {
+			uniques++
+		}
+	}
+
+	if counts != 1 {
+		t.Errorf("got %d hits lines, want exactly 1 (aggregated)", counts)
+	}
+	if gauges != 1 {
+		t.Errorf("got %d temp lines, want exactly 1 (aggregated)", gauges)
+	}
+	if uniques != 2 {
+		t.Errorf("got %d distinct users lines, want 2 (deduped)", uniques)
+	}
+}
+
+func TestMetricAggregatorIgnoresSampleRate(t *testing.T) {
+	old := randFloat
+	defer func() { randFloat = old }()
+	randFloat = func() float32 { return 1 } // would always be skipped if sampling applied
+
+	sink := NewMemorySink()
+	c := NewWithSink(sink, Aggregation(time.Hour), SampleRate(0.1))
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		c.Count("hits", 1)
+	}
+	c.Gauge("temp", 42)
+	for i := 0; i < n; i++ {
+		c.Unique("users", "alice")
+	}
+	c.Close()
+
+	var total float64
+	var sawGauge, uniques bool
+	for _, m := range sink.Metrics {
+		switch m.Bucket {
+		case "hits":
+			total += m.Value.(float64)
+			if m.Rate != 1 {
+				t.Errorf("aggregated count rate = %v, want 1 (accumulated total is exact)", m.Rate)
+			}
+		case "temp":
+			sawGauge = true
+			if m.Value != 42 {
+				t.Errorf("aggregated gauge = %v, want 42 (SampleRate must not drop it before it reaches the aggregator)", m.Value)
+			}
+		}
+	}
+	for _, u := range sink.Uniques {
+		if u.Bucket == "users" {
+			uniques = true
+		}
+	}
+	if total != n {
+		t.Errorf("hits total = %v, want %d (SampleRate must not apply to the aggregated path)", total, n)
+	}
+	if !sawGauge {
+		t.Error("no temp gauge emitted (SampleRate must not drop it before it reaches the aggregator)")
+	}
+	if !uniques {
+		t.Error("no users unique emitted (SampleRate must not drop it before it reaches the aggregator)")
+	}
+}
+
+func TestMetricAggregatorContextCount(t *testing.T) {
+	c := NewWithSink(NewMemorySink())
+	a := newMetricAggregator(c, time.Hour)
+	defer a.stop()
+
+	a.addCount("a", "", 1)
+	a.addCount("a", "", 1) // same key, should not add a new context
+	a.addCount("b", "", 1)
+	a.setGauge("g", "", 1)
+
+	if got := a.contextCount(); got != 3 {
+		t.Errorf("contextCount() = %d, want 3", got)
+	}
+}
+
+func TestCloseStopsAggregatorGoroutineEvenWhenMuted(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	c, err := New(Mute(true), Aggregation(time.Millisecond))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	c.Close()
+
+	// agg.stop() closes the channel the aggregator's ticker goroutine selects
+	// on; give that goroutine up to a second to observe it and exit.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		runtime.Gosched()
+	}
+	if got := runtime.NumGoroutine(); got > before {
+		t.Errorf("NumGoroutine() = %d after Close, want <= %d (aggregator goroutine leaked)", got, before)
+	}
+}
@@ -0,0 +1,219 @@
+package statsd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A conn is the buffered connection used by a Client to talk to a StatsD
+// daemon. Metrics are appended to an internal buffer and flushed either when
+// it would no longer fit, when the flush period elapses or when explicitly
+// asked to.
+type conn struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	w   io.WriteCloser
+
+	addr          string
+	network       string
+	maxPacketSize int
+	tagFormat     TagFormat
+	errorHandler  func(error)
+
+	closed bool
+
+	// tm holds the atomic counters backing the client's self-telemetry, when
+	// enabled with the Telemetry option. They are always maintained, at
+	// negligible cost, so that Telemetry can be turned on or off freely.
+	tm telemetry
+}
+
+// telemetry is the set of atomic, cumulative counters a conn maintains about
+// its own activity, reported as StatsD metrics by a telemetryReporter.
+type telemetry struct {
+	counts         int64
+	gauges         int64
+	timings        int64
+	histograms     int64
+	distributions  int64
+	sets           int64
+	events         int64
+	serviceChecks  int64
+	bytesSent      int64
+	bytesDropped   int64
+	packetsSent    int64
+	packetsDropped int64
+}
+
+func newConn(conf ConnConfig, muted bool) (*conn, error) {
+	c := &conn{
+		addr:          conf.Addr,
+		network:       conf.Network,
+		maxPacketSize: conf.MaxPacketSize,
+		tagFormat:     conf.TagFormat,
+		errorHandler:  conf.ErrorHandler,
+	}
+	c.buf.Grow(c.maxPacketSize)
+
+	if muted {
+		return c, nil
+	}
+
+	w, err := net.Dial(c.network, c.addr)
+	if err != nil {
+		c.handleError(err)
+		return c, err
+	}
+	c.w = w
+
+	if conf.FlushPeriod > 0 {
+		go c.flushLoop(conf.FlushPeriod)
+	}
+
+	return c, nil
+}
+
+func (c *conn) flushLoop(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return
+		}
+		c.flush(0)
+		c.mu.Unlock()
+	}
+}
+
+// WriteMetric appends a "prefix.bucket:value|type[|@rate]tags" line to the
+// buffer. typ is the wire type suffix: "c", "g", "ms", "h" or "d".
+func (c *conn) WriteMetric(prefix, bucket string, value interface{}, typ string, rate float32, tags string) {
+	c.countSample(typ)
+	c.writeMetricLine(prefix, bucket, value, typ, rate, tags)
+}
+
+// writeMetricLine appends the line without recording it against tm, for
+// writers (the telemetryReporter) that report on tm themselves and must not
+// count their own output as a sample.
+func (c *conn) writeMetricLine(prefix, bucket string, value interface{}, typ string, rate float32, tags string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	line := fmt.Sprintf("%s%s:%v|%s", prefix, bucket, value, typ)
+	if rate != 1 {
+		line += fmt.Sprintf("|@%s", formatRate(rate))
+	}
+	line += tags
+
+	c.write(line)
+}
+
+// countSample records, for self-telemetry, that a sample of the given wire
+// type suffix is about to be written.
+func (c *conn) countSample(typ string) {
+	switch typ {
+	case "c":
+		atomic.AddInt64(&c.tm.counts, 1)
+	case "g":
+		atomic.AddInt64(&c.tm.gauges, 1)
+	case "ms":
+		atomic.AddInt64(&c.tm.timings, 1)
+	case "h":
+		atomic.AddInt64(&c.tm.histograms, 1)
+	case "d":
+		atomic.AddInt64(&c.tm.distributions, 1)
+	}
+}
+
+// WriteUnique appends a "prefix.bucket:value|s" line to the buffer.
+func (c *conn) WriteUnique(prefix, bucket, value, tags string) {
+	atomic.AddInt64(&c.tm.sets, 1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.write(fmt.Sprintf("%s%s:%s|s%s", prefix, bucket, value, tags))
+}
+
+// Flush writes the buffer out immediately.
+func (c *conn) Flush() {
+	c.mu.Lock()
+	c.flush(0)
+	c.mu.Unlock()
+}
+
+// Close flushes the buffer and closes the underlying connection. The conn
+// must not be used afterward.
+func (c *conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flush(0)
+	err := c.w.Close()
+	c.handleError(err)
+	c.closed = true
+	return err
+}
+
+// write appends line to the buffer, flushing first if it would not fit.
+// c.mu must be held by the caller.
+func (c *conn) write(line string) {
+	if c.closed {
+		return
+	}
+
+	extra := len(line)
+	if c.buf.Len() > 0 {
+		extra++ // for the "\n" separator
+	}
+	if c.buf.Len()+extra > c.maxPacketSize {
+		c.flush(extra)
+	}
+
+	if c.buf.Len() > 0 {
+		_ = c.buf.WriteByte('\n')
+	}
+	_, _ = c.buf.WriteString(line)
+}
+
+// flush writes the buffer out and resets it, growing it back to extra bytes
+// of headroom if that is larger than the default packet size. c.mu must be
+// held by the caller.
+func (c *conn) flush(extra int) {
+	if c.buf.Len() == 0 || c.w == nil {
+		return
+	}
+
+	n := c.buf.Len()
+	_, err := c.w.Write(c.buf.Bytes())
+	if err != nil {
+		atomic.AddInt64(&c.tm.bytesDropped, int64(n))
+		atomic.AddInt64(&c.tm.packetsDropped, 1)
+	} else {
+		atomic.AddInt64(&c.tm.bytesSent, int64(n))
+		atomic.AddInt64(&c.tm.packetsSent, 1)
+	}
+	c.handleError(err)
+	c.buf.Reset()
+	if extra > c.maxPacketSize {
+		c.buf.Grow(extra)
+	}
+}
+
+func (c *conn) handleError(err error) {
+	if err != nil && c.errorHandler != nil {
+		c.errorHandler(err)
+	}
+}
+
+func formatRate(rate float32) string {
+	return fmt.Sprintf("%g", rate)
+}
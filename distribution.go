@@ -0,0 +1,237 @@
+package statsd
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// distReservoirSize is the number of samples kept per (bucket, tags) key
+// between two aggregation windows.
+const distReservoirSize = 1024
+
+// ClientSideAggregation turns on client-side aggregation of Distribution
+// metrics. Instead of sending every value over the wire, the Client keeps a
+// bounded reservoir of samples per bucket and tag set and, every window,
+// flushes count/sum/min/max/avg and the requested percentiles as gauges.
+// count, sum, min, max and avg are tracked over every value seen and are
+// exact; only the percentiles are approximated from the reservoir.
+//
+// This trades percentile accuracy (the reservoir is sampled, not exact) for
+// a large reduction in wire volume on high-frequency distributions. This
+// option is ignored in Client.Clone().
+func ClientSideAggregation(window time.Duration, percentiles []float64) Option {
+	return Option(func(c *config) {
+		c.Client.DistWindow = window
+		c.Client.DistPercentiles = percentiles
+	})
+}
+
+// distKey identifies a distribution's reservoir: its fully prefixed bucket
+// name and its already-formatted tag suffix.
+type distKey struct {
+	metric string
+	tags   string
+}
+
+// distAggregator keeps one reservoir per distKey and periodically rotates
+// them into aggregated gauge metrics written through a Client's sink.
+type distAggregator struct {
+	c           *Client
+	percentiles []float64
+	reservoirs  sync.Map // distKey -> *distReservoir
+	stopOnce    sync.Once
+	done        chan struct{}
+}
+
+func newDistAggregator(c *Client, window time.Duration, percentiles []float64) *distAggregator {
+	a := &distAggregator{
+		c:           c,
+		percentiles: percentiles,
+		done:        make(chan struct{}),
+	}
+	go a.loop(window)
+	return a
+}
+
+func (a *distAggregator) loop(window time.Duration) {
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.flush()
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// stop rotates any remaining samples out and shuts the aggregator down. It
+// is safe to call more than once. The final flush happens synchronously, so
+// every sample added before stop() returns has been written to the sink.
+func (a *distAggregator) stop() {
+	a.stopOnce.Do(func() {
+		close(a.done)
+		a.flush()
+	})
+}
+
+func (a *distAggregator) add(metric, tags string, value float64) {
+	key := distKey{metric: metric, tags: tags}
+	v, _ := a.reservoirs.LoadOrStore(key, newDistReservoir(distReservoirSize))
+	v.(*distReservoir).add(value)
+}
+
+func (a *distAggregator) flush() {
+	a.reservoirs.Range(func(k, v interface{}) bool {
+		key := k.(distKey)
+		stats, ok := v.(*distReservoir).rotate()
+		if !ok {
+			return true
+		}
+		a.emit(key, stats)
+		return true
+	})
+}
+
+func (a *distAggregator) emit(key distKey, stats distStats) {
+	gauge := func(suffix string, value float64) {
+		a.c.sink.WriteMetric("", key.metric+"."+suffix, value, "g", 1, key.tags)
+	}
+
+	gauge("count", float64(stats.count))
+	gauge("sum", stats.sum)
+	gauge("min", stats.min)
+	gauge("max", stats.max)
+	gauge("avg", stats.sum/float64(stats.count))
+	for _, p := range a.percentiles {
+		gauge(percentileSuffix(p), stats.percentile(p))
+	}
+}
+
+func percentileSuffix(p float64) string {
+	return "p" + strconv.FormatFloat(p*100, 'f', -1, 64)
+}
+
+// toFloat64 converts the numeric types accepted by Client.Distribution into
+// a float64 for use in a reservoir. ok is false for unsupported types.
+func toFloat64(value interface{}) (f float64, ok bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// distStats is the set of aggregates computed from a reservoir at rotation
+// time. values holds the sorted samples so percentile() can be computed
+// lazily for each requested percentile.
+type distStats struct {
+	count  int64
+	sum    float64
+	min    float64
+	max    float64
+	values []float64 // sorted
+}
+
+func (s distStats) percentile(p float64) float64 {
+	if len(s.values) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(s.values)))
+	if i >= len(s.values) {
+		i = len(s.values) - 1
+	}
+	return s.values[i]
+}
+
+// distReservoir is a fixed-size reservoir sample (Vitter's algorithm R) of
+// the values observed for a single (bucket, tags) key between two
+// aggregation windows. count, sum, min and max are tracked over every value
+// seen, not just the sampled ones kept in values, so they stay exact even
+// once the reservoir is full.
+type distReservoir struct {
+	mu     sync.Mutex
+	size   int
+	count  int64
+	sum    float64
+	min    float64
+	max    float64
+	values []float64
+}
+
+func newDistReservoir(size int) *distReservoir {
+	return &distReservoir{size: size, values: make([]float64, 0, size)}
+}
+
+func (r *distReservoir) add(v float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.count == 0 || v < r.min {
+		r.min = v
+	}
+	if r.count == 0 || v > r.max {
+		r.max = v
+	}
+	r.sum += v
+	r.count++
+
+	if len(r.values) < r.size {
+		r.values = append(r.values, v)
+		return
+	}
+	if j := int(randFloat() * float32(r.count)); j < r.size {
+		r.values[j] = v
+	}
+}
+
+// rotate returns the aggregated stats for the samples seen since the last
+// rotation and resets the reservoir. ok is false if no sample was observed.
+// count, sum, min and max are exact over every value seen; only the
+// percentiles derived from values are approximated from the reservoir.
+func (r *distReservoir) rotate() (stats distStats, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.count == 0 {
+		return distStats{}, false
+	}
+
+	values := make([]float64, len(r.values))
+	copy(values, r.values)
+	sort.Float64s(values)
+
+	stats = distStats{count: r.count, sum: r.sum, min: r.min, max: r.max, values: values}
+
+	r.values = r.values[:0]
+	r.count = 0
+	r.sum, r.min, r.max = 0, 0, 0
+	return stats, true
+}
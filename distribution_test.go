@@ -0,0 +1,190 @@
+package statsd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDistReservoirRotateWithinCapacity(t *testing.T) {
+	r := newDistReservoir(4)
+	for _, v := range []float64{1, 2, 3, 4} {
+		r.add(v)
+	}
+
+	stats, ok := r.rotate()
+	if !ok {
+		t.Fatal("rotate() = false, want true")
+	}
+	if stats.count != 4 {
+		t.Errorf("count = %d, want 4", stats.count)
+	}
+	if stats.sum != 10 {
+		t.Errorf("sum = %v, want 10", stats.sum)
+	}
+	if stats.min != 1 {
+		t.Errorf("min = %v, want 1", stats.min)
+	}
+	if stats.max != 4 {
+		t.Errorf("max = %v, want 4", stats.max)
+	}
+	if len(stats.values) != 4 {
+		t.Errorf("len(values) = %d, want 4", len(stats.values))
+	}
+}
+
+func TestDistReservoirRotateEmpty(t *testing.T) {
+	r := newDistReservoir(4)
+	if _, ok := r.rotate(); ok {
+		t.Fatal("rotate() on an empty reservoir = true, want false")
+	}
+}
+
+// TestDistReservoirExactBeyondCapacity asserts that count/sum/min/max stay
+// exact over every value added even once the reservoir has evicted samples,
+// regardless of which samples randFloat happens to keep.
+func TestDistReservoirExactBeyondCapacity(t *testing.T) {
+	old := randFloat
+	defer func() { randFloat = old }()
+	randFloat = func() float32 { return 1 } // never evicts an existing sample
+
+	r := newDistReservoir(2)
+	values := []float64{5, -3, 100, 1, 0, -50}
+	for _, v := range values {
+		r.add(v)
+	}
+
+	stats, ok := r.rotate()
+	if !ok {
+		t.Fatal("rotate() = false, want true")
+	}
+	if stats.count != int64(len(values)) {
+		t.Errorf("count = %d, want %d", stats.count, len(values))
+	}
+	if stats.sum != 53 {
+		t.Errorf("sum = %v, want 53", stats.sum)
+	}
+	if stats.min != -50 {
+		t.Errorf("min = %v, want -50", stats.min)
+	}
+	if stats.max != 100 {
+		t.Errorf("max = %v, want 100", stats.max)
+	}
+	// The reservoir is bounded to its configured size even though more
+	// values were observed.
+	if len(stats.values) != 2 {
+		t.Errorf("len(values) = %d, want 2", len(stats.values))
+	}
+}
+
+func TestDistReservoirResetsAfterRotate(t *testing.T) {
+	r := newDistReservoir(4)
+	r.add(10)
+	if _, ok := r.rotate(); !ok {
+		t.Fatal("first rotate() = false, want true")
+	}
+
+	if _, ok := r.rotate(); ok {
+		t.Fatal("rotate() right after a rotate = true, want false (reservoir should be empty)")
+	}
+
+	r.add(-1)
+	stats, ok := r.rotate()
+	if !ok {
+		t.Fatal("rotate() after adding post-reset = false, want true")
+	}
+	if stats.count != 1 || stats.sum != -1 || stats.min != -1 || stats.max != -1 {
+		t.Errorf("stats after reset = %+v, want count=1 sum=min=max=-1", stats)
+	}
+}
+
+func TestDistStatsPercentile(t *testing.T) {
+	s := distStats{values: []float64{1, 2, 3, 4, 5}}
+
+	if got := s.percentile(0); got != 1 {
+		t.Errorf("p0 = %v, want 1", got)
+	}
+	if got := s.percentile(0.999); got != 5 {
+		t.Errorf("p99.9 = %v, want 5", got)
+	}
+
+	empty := distStats{}
+	if got := empty.percentile(0.5); got != 0 {
+		t.Errorf("percentile on empty stats = %v, want 0", got)
+	}
+}
+
+func TestPercentileSuffix(t *testing.T) {
+	cases := map[float64]string{
+		0.5:    "p50",
+		0.95:   "p95",
+		0.999:  "p99.9",
+		0.9999: "p99.99",
+	}
+	for p, want := range cases {
+		if got := percentileSuffix(p); got != want {
+			t.Errorf("percentileSuffix(%v) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func TestToFloat64(t *testing.T) {
+	cases := []struct {
+		in   interface{}
+		want float64
+		ok   bool
+	}{
+		{int(3), 3, true},
+		{int64(-2), -2, true},
+		{uint32(7), 7, true},
+		{float32(1.5), 1.5, true},
+		{float64(2.5), 2.5, true},
+		{"not a number", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := toFloat64(c.in)
+		if ok != c.ok || (ok && got != c.want) {
+			t.Errorf("toFloat64(%v) = (%v, %v), want (%v, %v)", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+// TestClientDistributionAggregationExactStats exercises the full
+// Client.Distribution -> distAggregator -> Sink path through a MemorySink,
+// and checks that count/sum/min/max/avg are exact even though the reservoir
+// is far smaller than the number of samples observed.
+func TestClientDistributionAggregationExactStats(t *testing.T) {
+	sink := NewMemorySink()
+	c := NewWithSink(sink, ClientSideAggregation(time.Hour, []float64{0.5}))
+
+	var sum float64
+	for i := 1; i <= distReservoirSize*4; i++ {
+		c.Distribution("bucket", i)
+		sum += float64(i)
+	}
+	c.Close() // stops the aggregator, forcing a final flush
+
+	byBucket := map[string]MemoryMetric{}
+	for _, m := range sink.Metrics {
+		byBucket[m.Bucket] = m
+	}
+
+	count, ok := byBucket["bucket.count"]
+	if !ok {
+		t.Fatal("no bucket.count metric emitted")
+	}
+	if count.Value.(float64) != float64(distReservoirSize*4) {
+		t.Errorf("count = %v, want %d", count.Value, distReservoirSize*4)
+	}
+	if got := byBucket["bucket.sum"].Value.(float64); got != sum {
+		t.Errorf("sum = %v, want %v", got, sum)
+	}
+	if got := byBucket["bucket.min"].Value.(float64); got != 1 {
+		t.Errorf("min = %v, want 1", got)
+	}
+	if got := byBucket["bucket.max"].Value.(float64); got != float64(distReservoirSize*4) {
+		t.Errorf("max = %v, want %d", got, distReservoirSize*4)
+	}
+	if got := byBucket["bucket.avg"].Value.(float64); got != sum/float64(distReservoirSize*4) {
+		t.Errorf("avg = %v, want %v", got, sum/float64(distReservoirSize*4))
+	}
+}
@@ -0,0 +1,196 @@
+package statsd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Priority represents the priority of an Event. This is a Datadog extension
+// and is ignored unless the Datadog tag format is used.
+type Priority uint8
+
+const (
+	// PriorityNormal is the default priority of an Event.
+	PriorityNormal Priority = iota
+	// PriorityLow marks an Event as low priority.
+	PriorityLow
+)
+
+func (p Priority) String() string {
+	if p == PriorityLow {
+		return "low"
+	}
+	return "normal"
+}
+
+// AlertType represents the alert type of an Event. This is a Datadog
+// extension and is ignored unless the Datadog tag format is used.
+type AlertType uint8
+
+const (
+	// AlertInfo is the default alert type of an Event.
+	AlertInfo AlertType = iota
+	AlertError
+	AlertWarning
+	AlertSuccess
+)
+
+func (t AlertType) String() string {
+	switch t {
+	case AlertError:
+		return "error"
+	case AlertWarning:
+		return "warning"
+	case AlertSuccess:
+		return "success"
+	default:
+		return "info"
+	}
+}
+
+// EventConfig holds the optional fields of an Event, as set by EventOptions.
+// It is exported so that a Sink implementation can inspect it.
+type EventConfig struct {
+	HasTimestamp   bool
+	Timestamp      time.Time
+	Hostname       string
+	AggregationKey string
+	HasPriority    bool
+	Priority       Priority
+	SourceType     string
+	HasAlertType   bool
+	AlertType      AlertType
+	Tags           *Tags
+}
+
+// An EventOption represents an option for Client.Event.
+type EventOption func(*EventConfig)
+
+// EventTimestamp sets the timestamp of the event. By default, the StatsD
+// daemon uses the time it received the event at.
+func EventTimestamp(t time.Time) EventOption {
+	return EventOption(func(e *EventConfig) {
+		e.HasTimestamp = true
+		e.Timestamp = t
+	})
+}
+
+// EventHostname sets the hostname the event should be associated with.
+func EventHostname(hostname string) EventOption {
+	return EventOption(func(e *EventConfig) {
+		e.Hostname = hostname
+	})
+}
+
+// EventAggregationKey sets the key by which the StatsD daemon groups the
+// event with related events.
+func EventAggregationKey(key string) EventOption {
+	return EventOption(func(e *EventConfig) {
+		e.AggregationKey = key
+	})
+}
+
+// EventPriority sets the priority of the event.
+//
+// By default, the priority is PriorityNormal.
+func EventPriority(p Priority) EventOption {
+	return EventOption(func(e *EventConfig) {
+		e.HasPriority = true
+		e.Priority = p
+	})
+}
+
+// EventSourceType sets the source type of the event (e.g. "nagios", "chef").
+func EventSourceType(sourceType string) EventOption {
+	return EventOption(func(e *EventConfig) {
+		e.SourceType = sourceType
+	})
+}
+
+// EventAlertType sets the alert type of the event.
+//
+// By default, the alert type is AlertInfo.
+func EventAlertType(t AlertType) EventOption {
+	return EventOption(func(e *EventConfig) {
+		e.HasAlertType = true
+		e.AlertType = t
+	})
+}
+
+// EventTags appends tags to the event, on top of the Client's common tags.
+//
+// The tags must be set as key-value pairs. If the number of tags is not
+// even, EventTags panics.
+func EventTags(tags ...string) EventOption {
+	if len(tags)%2 != 0 {
+		panic("statsd: EventTags only accepts an even number of arguments")
+	}
+
+	return EventOption(func(e *EventConfig) {
+		e.Tags.append(newTags(tags...))
+	})
+}
+
+// Event sends a Datadog event made up of title and text.
+//
+// Event is a no-op unless the Client was configured with TagsFormat(Datadog),
+// since events are a Datadog-specific extension to the StatsD protocol.
+func (c *Client) Event(title, text string, opts ...EventOption) {
+	if c.muted || c.tagFormat != Datadog {
+		return
+	}
+
+	cfg := EventConfig{Tags: emptyTags()}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	cfg.Tags.append(c.tags)
+
+	c.sink.WriteEvent(c.prefix, title, text, cfg, cfg.Tags.format(c.tagFormat, c.containerID))
+}
+
+// escapeNewlines replaces literal newlines with the two-character escape
+// "\n", since the wire protocol uses "\n" to separate packed metric lines
+// and a literal newline in an event or service check body would otherwise
+// split it into garbage.
+func escapeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// WriteEvent appends a DogStatsD event line to the buffer. c.mu must not be
+// held by the caller.
+func (c *conn) WriteEvent(prefix, title, text string, cfg EventConfig, tags string) {
+	atomic.AddInt64(&c.tm.events, 1)
+
+	title = escapeNewlines(prefix + title)
+	text = escapeNewlines(text)
+
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "_e{%d,%d}:%s|%s", len(title), len(text), title, text)
+	if cfg.HasTimestamp {
+		_, _ = fmt.Fprintf(&buf, "|d:%d", cfg.Timestamp.Unix())
+	}
+	if cfg.Hostname != "" {
+		_, _ = fmt.Fprintf(&buf, "|h:%s", cfg.Hostname)
+	}
+	if cfg.AggregationKey != "" {
+		_, _ = fmt.Fprintf(&buf, "|k:%s", cfg.AggregationKey)
+	}
+	if cfg.HasPriority {
+		_, _ = fmt.Fprintf(&buf, "|p:%s", cfg.Priority)
+	}
+	if cfg.SourceType != "" {
+		_, _ = fmt.Fprintf(&buf, "|s:%s", cfg.SourceType)
+	}
+	if cfg.HasAlertType {
+		_, _ = fmt.Fprintf(&buf, "|t:%s", cfg.AlertType)
+	}
+	buf.WriteString(tags)
+
+	c.mu.Lock()
+	c.write(buf.String())
+	c.mu.Unlock()
+}
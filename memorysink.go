@@ -0,0 +1,92 @@
+package statsd
+
+import "sync"
+
+// A MemoryMetric records a single Sink.WriteMetric call captured by a
+// MemorySink.
+type MemoryMetric struct {
+	Prefix, Bucket string
+	Value          interface{}
+	Type           string
+	Rate           float32
+	Tags           string
+}
+
+// A MemoryUnique records a single Sink.WriteUnique call captured by a
+// MemorySink.
+type MemoryUnique struct {
+	Prefix, Bucket, Value, Tags string
+}
+
+// A MemoryEvent records a single Sink.WriteEvent call captured by a
+// MemorySink.
+type MemoryEvent struct {
+	Prefix, Title, Text string
+	Config              EventConfig
+	Tags                string
+}
+
+// A MemoryServiceCheck records a single Sink.WriteServiceCheck call captured
+// by a MemorySink.
+type MemoryServiceCheck struct {
+	Prefix, Name string
+	Status       ServiceCheckStatus
+	Config       SCConfig
+	Tags         string
+}
+
+// A MemorySink is a Sink that records every write in memory instead of
+// sending it anywhere. It is meant for unit tests that want to assert on
+// what a Client sent without spinning up a UDP listener.
+type MemorySink struct {
+	mu sync.Mutex
+
+	Metrics       []MemoryMetric
+	Uniques       []MemoryUnique
+	Events        []MemoryEvent
+	ServiceChecks []MemoryServiceCheck
+	Flushes       int
+	Closed        bool
+}
+
+// NewMemorySink returns a new MemorySink.
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) WriteMetric(prefix, bucket string, value interface{}, typ string, rate float32, tags string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Metrics = append(s.Metrics, MemoryMetric{prefix, bucket, value, typ, rate, tags})
+}
+
+func (s *MemorySink) WriteUnique(prefix, bucket, value, tags string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Uniques = append(s.Uniques, MemoryUnique{prefix, bucket, value, tags})
+}
+
+func (s *MemorySink) WriteEvent(prefix, title, text string, cfg EventConfig, tags string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Events = append(s.Events, MemoryEvent{prefix, title, text, cfg, tags})
+}
+
+func (s *MemorySink) WriteServiceCheck(prefix, name string, status ServiceCheckStatus, cfg SCConfig, tags string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ServiceChecks = append(s.ServiceChecks, MemoryServiceCheck{prefix, name, status, cfg, tags})
+}
+
+func (s *MemorySink) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Flushes++
+}
+
+func (s *MemorySink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Closed = true
+	return nil
+}
@@ -0,0 +1,53 @@
+package statsd
+
+// A MultiSink fans every write out to multiple Sinks, e.g. to route a
+// Client's metrics to more than one backend at once.
+type MultiSink struct {
+	sinks []Sink
+}
+
+// NewMultiSink returns a Sink that writes through to every one of sinks.
+func NewMultiSink(sinks ...Sink) Sink {
+	return &MultiSink{sinks: sinks}
+}
+
+func (m *MultiSink) WriteMetric(prefix, bucket string, value interface{}, typ string, rate float32, tags string) {
+	for _, s := range m.sinks {
+		s.WriteMetric(prefix, bucket, value, typ, rate, tags)
+	}
+}
+
+func (m *MultiSink) WriteUnique(prefix, bucket, value, tags string) {
+	for _, s := range m.sinks {
+		s.WriteUnique(prefix, bucket, value, tags)
+	}
+}
+
+func (m *MultiSink) WriteEvent(prefix, title, text string, cfg EventConfig, tags string) {
+	for _, s := range m.sinks {
+		s.WriteEvent(prefix, title, text, cfg, tags)
+	}
+}
+
+func (m *MultiSink) WriteServiceCheck(prefix, name string, status ServiceCheckStatus, cfg SCConfig, tags string) {
+	for _, s := range m.sinks {
+		s.WriteServiceCheck(prefix, name, status, cfg, tags)
+	}
+}
+
+func (m *MultiSink) Flush() {
+	for _, s := range m.sinks {
+		s.Flush()
+	}
+}
+
+// Close closes every sink, returning the first error encountered, if any.
+func (m *MultiSink) Close() error {
+	var firstErr error
+	for _, s := range m.sinks {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -7,18 +7,26 @@ import (
 )
 
 type config struct {
-	Conn   connConfig
+	Conn   ConnConfig
 	Client clientConfig
 }
 
 type clientConfig struct {
-	Muted  bool
-	Rate   float32
-	Prefix string
-	Tags   *Tags
+	Muted             bool
+	Rate              float32
+	Prefix            string
+	Tags              *Tags
+	DistWindow        time.Duration
+	DistPercentiles   []float64
+	AggInterval       time.Duration
+	TelemetryPrefix   string
+	TelemetryInterval time.Duration
+	OriginDetection   bool
+	ContainerID       string
+	Transport         string
 }
 
-type connConfig struct {
+type ConnConfig struct {
 	Addr          string
 	ErrorHandler  func(error)
 	FlushPeriod   time.Duration
@@ -202,12 +210,16 @@ func (this *Tags) clone() *Tags {
 	return &Tags{kvs, keys}
 }
 
-func (this *Tags) format(tf TagFormat) string {
-	if this.numTags() == 0 {
-		return ""
-	}
+// format renders the tags using the given TagFormat. If containerID is non
+// empty and tf is Datadog, a trailing "|c:<containerID>" is appended after
+// the tag block, the way OriginDetection/ContainerID tags metrics with the
+// container they were sent from.
+func (this *Tags) format(tf TagFormat, containerID string) string {
 	switch tf {
 	case InfluxDB:
+		if this.numTags() == 0 {
+			return ""
+		}
 		var buf bytes.Buffer
 		for _, k := range this.keys {
 			_ = buf.WriteByte(',')
@@ -217,15 +229,24 @@ func (this *Tags) format(tf TagFormat) string {
 		}
 		return buf.String()
 	case Datadog:
-		buf := bytes.NewBufferString("|#")
-		for _, k := range this.keys {
-			_, _ = buf.WriteString(k)
-			_ = buf.WriteByte(':')
-			_, _ = buf.WriteString(this.kvs[k])
-			_ = buf.WriteByte(',')
+		if this.numTags() == 0 && containerID == "" {
+			return ""
+		}
+		var buf bytes.Buffer
+		if this.numTags() > 0 {
+			buf.WriteString("|#")
+			for _, k := range this.keys {
+				_, _ = buf.WriteString(k)
+				_ = buf.WriteByte(':')
+				_, _ = buf.WriteString(this.kvs[k])
+				_ = buf.WriteByte(',')
+			}
+		}
+		result := strings.TrimSuffix(buf.String(), ",")
+		if containerID != "" {
+			result += "|c:" + containerID
 		}
-		result := buf.String()
-		return strings.TrimSuffix(result, ",")
+		return result
 	default:
 		return ""
 	}
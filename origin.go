@@ -0,0 +1,97 @@
+package statsd
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// containerIDRegexp matches a 64 character hex container ID, the last path
+// segment of a cgroup entry for a containerized process.
+var containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// OriginDetection turns on automatic container ID detection: at New() time,
+// the Client inspects /proc/self/cgroup (falling back to
+// /proc/self/mountinfo for cgroup v2 hosts) to find the ID of the container
+// it is running in, and tags every metric sent with TagsFormat(Datadog) with
+// "|c:<container-id>".
+//
+// Detection is resilient to a missing /proc (e.g. on non-Linux platforms):
+// it silently leaves the Client without a container ID rather than failing
+// New(). This option is ignored in Client.Clone().
+func OriginDetection() Option {
+	return Option(func(c *config) {
+		c.Client.OriginDetection = true
+	})
+}
+
+// ContainerID overrides automatic container ID detection with an explicit
+// ID. This option is ignored in Client.Clone().
+func ContainerID(id string) Option {
+	return Option(func(c *config) {
+		c.Client.ContainerID = id
+	})
+}
+
+// detectContainerID returns the ID of the container the current process is
+// running in, if any can be found.
+func detectContainerID() (string, bool) {
+	if id, ok := containerIDFromCgroup("/proc/self/cgroup"); ok {
+		return id, true
+	}
+	return containerIDFromMountinfo("/proc/self/mountinfo")
+}
+
+// containerIDFromCgroup parses a cgroup v1 style /proc/self/cgroup file,
+// whose lines look like "4:memory:/docker/<id>", and returns the container
+// ID found in the first line whose cgroup path holds one (see
+// lastHexSegment).
+func containerIDFromCgroup(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		cgroupPath := fields[len(fields)-1]
+		if id := lastHexSegment(cgroupPath); id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// containerIDFromMountinfo parses /proc/self/mountinfo, used as a fallback
+// on cgroup v2 hosts where /proc/self/cgroup no longer carries a per-
+// container path.
+func containerIDFromMountinfo(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := lastHexSegment(scanner.Text()); id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// lastHexSegment returns the 64 character hex container ID found in the
+// rightmost "/"-separated segment of s that contains one, or "" if none does.
+func lastHexSegment(s string) string {
+	segments := strings.Split(s, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if containerIDRegexp.MatchString(segments[i]) {
+			return containerIDRegexp.FindString(segments[i])
+		}
+	}
+	return ""
+}
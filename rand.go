@@ -0,0 +1,7 @@
+package statsd
+
+import "math/rand"
+
+// randFloat returns a random float32 in [0, 1). It is a variable so tests
+// can stub it out.
+var randFloat = rand.Float32
@@ -0,0 +1,114 @@
+package statsd
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ServiceCheckStatus represents the status reported by a Client.ServiceCheck
+// call.
+type ServiceCheckStatus uint8
+
+const (
+	StatusOK ServiceCheckStatus = iota
+	StatusWarning
+	StatusCritical
+	StatusUnknown
+)
+
+// SCConfig holds the optional fields of a service check, as set by
+// SCOptions. It is exported so that a Sink implementation can inspect it.
+type SCConfig struct {
+	HasTimestamp bool
+	Timestamp    time.Time
+	Hostname     string
+	Message      string
+	Tags         *Tags
+}
+
+// An SCOption represents an option for Client.ServiceCheck.
+type SCOption func(*SCConfig)
+
+// SCTimestamp sets the timestamp of the service check. By default, the
+// StatsD daemon uses the time it received the service check at.
+func SCTimestamp(t time.Time) SCOption {
+	return SCOption(func(s *SCConfig) {
+		s.HasTimestamp = true
+		s.Timestamp = t
+	})
+}
+
+// SCHostname sets the hostname the service check should be associated with.
+func SCHostname(hostname string) SCOption {
+	return SCOption(func(s *SCConfig) {
+		s.Hostname = hostname
+	})
+}
+
+// SCMessage sets a message describing the current state of the service
+// check. It is displayed with service checks that have a status of
+// StatusWarning, StatusCritical or StatusUnknown.
+func SCMessage(message string) SCOption {
+	return SCOption(func(s *SCConfig) {
+		s.Message = message
+	})
+}
+
+// SCTags appends tags to the service check, on top of the Client's common
+// tags.
+//
+// The tags must be set as key-value pairs. If the number of tags is not
+// even, SCTags panics.
+func SCTags(tags ...string) SCOption {
+	if len(tags)%2 != 0 {
+		panic("statsd: SCTags only accepts an even number of arguments")
+	}
+
+	return SCOption(func(s *SCConfig) {
+		s.Tags.append(newTags(tags...))
+	})
+}
+
+// ServiceCheck sends a Datadog service check for name with the given status.
+//
+// ServiceCheck is a no-op unless the Client was configured with
+// TagsFormat(Datadog), since service checks are a Datadog-specific extension
+// to the StatsD protocol.
+func (c *Client) ServiceCheck(name string, status ServiceCheckStatus, opts ...SCOption) {
+	if c.muted || c.tagFormat != Datadog {
+		return
+	}
+
+	cfg := SCConfig{Tags: emptyTags()}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	cfg.Tags.append(c.tags)
+
+	c.sink.WriteServiceCheck(c.prefix, name, status, cfg, cfg.Tags.format(c.tagFormat, c.containerID))
+}
+
+// WriteServiceCheck appends a DogStatsD service check line to the buffer.
+// c.mu must not be held by the caller.
+func (c *conn) WriteServiceCheck(prefix string, name string, status ServiceCheckStatus, cfg SCConfig, tags string) {
+	atomic.AddInt64(&c.tm.serviceChecks, 1)
+
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "_sc|%s%s|%d", prefix, name, status)
+	if cfg.HasTimestamp {
+		_, _ = fmt.Fprintf(&buf, "|d:%d", cfg.Timestamp.Unix())
+	}
+	if cfg.Hostname != "" {
+		_, _ = fmt.Fprintf(&buf, "|h:%s", cfg.Hostname)
+	}
+	buf.WriteString(tags)
+	if cfg.Message != "" {
+		_, _ = fmt.Fprintf(&buf, "|m:%s", escapeNewlines(cfg.Message))
+	}
+
+	c.mu.Lock()
+	c.write(buf.String())
+	c.mu.Unlock()
+}
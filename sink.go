@@ -0,0 +1,43 @@
+package statsd
+
+// A Sink is the destination a Client writes its metrics, events and service
+// checks to. It lets Client be used without a real network connection: to
+// unit test code that emits metrics (NewMemorySink), to fan a Client's
+// output out to several backends at once (NewMultiSink), or to plug in a
+// transport other than the UDP/TCP one NewNetSink provides.
+type Sink interface {
+	// WriteMetric writes a count, gauge, timing, histogram or distribution
+	// sample. typ is the wire type suffix ("c", "g", "ms", "h" or "d").
+	WriteMetric(prefix, bucket string, value interface{}, typ string, rate float32, tags string)
+	// WriteUnique writes a set sample.
+	WriteUnique(prefix, bucket, value, tags string)
+	// WriteEvent writes a Datadog event.
+	WriteEvent(prefix, title, text string, cfg EventConfig, tags string)
+	// WriteServiceCheck writes a Datadog service check.
+	WriteServiceCheck(prefix, name string, status ServiceCheckStatus, cfg SCConfig, tags string)
+	// Flush writes out any buffered samples immediately.
+	Flush()
+	// Close flushes and releases the Sink's resources. The Sink must not be
+	// used afterward.
+	Close() error
+}
+
+// Transport sets the name reported as the client_transport tag on
+// self-telemetry metrics (see Telemetry). New sets it automatically from
+// the Network option; it only needs to be set explicitly when using
+// NewWithSink with a Sink that isn't NewNetSink.
+func Transport(name string) Option {
+	return Option(func(c *config) {
+		c.Client.Transport = name
+	})
+}
+
+// NewNetSink returns the UDP/TCP-backed Sink that New uses by default,
+// configured by Address, Network, FlushPeriod, MaxPacketSize, TagFormat and
+// ErrorHandler.
+//
+// Most callers should use New instead of constructing a NetSink directly;
+// NewNetSink is exposed for NewWithSink and for composing with NewMultiSink.
+func NewNetSink(conf ConnConfig) (Sink, error) {
+	return newConn(conf, false)
+}
@@ -6,12 +6,17 @@ import (
 
 // A Client represents a StatsD client.
 type Client struct {
-	conn      *conn
-	muted     bool
-	rate      float32
-	prefix    string
-	tags      *Tags
-	tagFormat TagFormat
+	sink        Sink
+	muted       bool
+	rate        float32
+	prefix      string
+	tags        *Tags
+	tagFormat   TagFormat
+	containerID string
+	transport   string
+	distAgg     *distAggregator
+	agg         *metricAggregator
+	telemetry   *telemetryReporter
 }
 
 // New returns a new Client.
@@ -22,7 +27,7 @@ func New(opts ...Option) (*Client, error) {
 			Rate: 1,
 			Tags: emptyTags(),
 		},
-		Conn: connConfig{
+		Conn: ConnConfig{
 			Addr:        ":8125",
 			FlushPeriod: 100 * time.Millisecond,
 			// Worst-case scenario:
@@ -35,61 +40,130 @@ func New(opts ...Option) (*Client, error) {
 		o(conf)
 	}
 
-	conn, err := newConn(conf.Conn, conf.Client.Muted)
+	sink, err := newConn(conf.Conn, conf.Client.Muted)
 	c := &Client{
-		conn:  conn,
+		sink:  sink,
 		muted: conf.Client.Muted,
 	}
 	if err != nil {
 		c.muted = true
 		return c, err
 	}
+	if conf.Client.Transport == "" {
+		conf.Client.Transport = conf.Conn.Network
+	}
+	c.init(conf)
+	return c, nil
+}
+
+// NewWithSink returns a new Client that writes through sink instead of
+// dialing a UDP/TCP connection. It is meant for unit tests (with
+// NewMemorySink), fanning a Client's output out to several backends (with
+// NewMultiSink), or plugging in a transport other than the one NewNetSink
+// provides.
+//
+// The Address, Network, FlushPeriod, MaxPacketSize and ErrorHandler options
+// only apply to NewNetSink and are ignored by NewWithSink; use Transport to
+// set the client_transport telemetry tag if needed.
+func NewWithSink(sink Sink, opts ...Option) *Client {
+	conf := &config{
+		Client: clientConfig{
+			Rate: 1,
+			Tags: emptyTags(),
+		},
+	}
+	for _, o := range opts {
+		o(conf)
+	}
+
+	c := &Client{sink: sink, muted: conf.Client.Muted}
+	c.init(conf)
+	return c
+}
+
+// init finishes configuring c from conf. c.sink and c.muted must already be
+// set.
+func (c *Client) init(conf *config) {
 	c.rate = conf.Client.Rate
 	c.prefix = conf.Client.Prefix
 	c.tags = conf.Client.Tags
 	c.tagFormat = conf.Conn.TagFormat
-	return c, nil
+	c.transport = conf.Client.Transport
+	if conf.Client.ContainerID != "" {
+		c.containerID = conf.Client.ContainerID
+	} else if conf.Client.OriginDetection {
+		c.containerID, _ = detectContainerID()
+	}
+	if conf.Client.DistWindow > 0 {
+		c.distAgg = newDistAggregator(c, conf.Client.DistWindow, conf.Client.DistPercentiles)
+	}
+	if conf.Client.AggInterval > 0 {
+		c.agg = newMetricAggregator(c, conf.Client.AggInterval)
+	}
+	if conf.Client.TelemetryInterval > 0 {
+		c.telemetry = newTelemetryReporter(c, conf.Client.TelemetryPrefix, conf.Client.TelemetryInterval)
+	}
 }
 
 // Clone returns a clone of the Client. The cloned Client inherits its
 // configuration from its parent.
 //
-// All cloned Clients share the same connection, so cloning a Client is a cheap
+// All cloned Clients share the same Sink, so cloning a Client is a cheap
 // operation.
 func (c *Client) Clone(opts ...Option) *Client {
-	tf := c.conn.tagFormat
 	conf := &config{
 		Client: clientConfig{
-			Rate:   c.rate,
-			Prefix: c.prefix,
-			Tags:   c.tags,
+			Rate:      c.rate,
+			Prefix:    c.prefix,
+			Tags:      c.tags,
+			Transport: c.transport,
 		},
 	}
 	for _, o := range opts {
 		o(conf)
 	}
 
-	clone := &Client{
-		conn:      c.conn,
-		muted:     c.muted || conf.Client.Muted,
-		rate:      conf.Client.Rate,
-		prefix:    conf.Client.Prefix,
-		tags:      conf.Client.Tags.clone(),
-		tagFormat: tf,
+	return &Client{
+		sink:        c.sink,
+		muted:       c.muted || conf.Client.Muted,
+		rate:        conf.Client.Rate,
+		prefix:      conf.Client.Prefix,
+		tags:        conf.Client.Tags.clone(),
+		tagFormat:   c.tagFormat,
+		containerID: c.containerID,
+		transport:   conf.Client.Transport,
+		distAgg:     c.distAgg,
+		agg:         c.agg,
+		telemetry:   c.telemetry,
 	}
-	clone.conn = c.conn
-	return clone
 }
 
 // Count adds n to bucket.
+//
+// When Aggregation is enabled, Count is not subject to SampleRate: the
+// accumulated total is exact, and sampling it on top of aggregating it would
+// only add noise without reducing wire volume any further.
 func (c *Client) Count(bucket string, n interface{}, metricTags ...string) {
-	if c.skip() {
+	if c.muted {
 		return
 	}
 
 	mTags := newTags(metricTags...)
 	mTags.append(c.tags)
-	c.conn.metric(c.prefix, bucket, n, "c", c.rate, mTags.format(c.tagFormat))
+	tags := mTags.format(c.tagFormat, c.containerID)
+
+	if c.agg != nil {
+		if f, ok := toFloat64(n); ok {
+			c.agg.addCount(c.prefix+bucket, tags, f)
+			return
+		}
+	}
+
+	if c.rate != 1 && randFloat() > c.rate {
+		return
+	}
+
+	c.sink.WriteMetric(c.prefix, bucket, n, "c", c.rate, tags)
 }
 
 func (c *Client) skip() bool {
@@ -102,14 +176,29 @@ func (c *Client) Increment(bucket string, metricTags ...string) {
 }
 
 // Gauge records an absolute value for the given bucket.
+//
+// When Aggregation is enabled, Gauge is not subject to SampleRate: the
+// aggregator already keeps only the last-observed value per interval, and
+// sampling on top of that would make it stale instead of exact.
 func (c *Client) Gauge(bucket string, value interface{}, metricTags ...string) {
-	if c.skip() {
+	if c.muted {
 		return
 	}
 
 	mTags := newTags(metricTags...)
 	mTags.append(c.tags)
-	c.conn.gauge(c.prefix, bucket, value, mTags.format(c.tagFormat))
+	tags := mTags.format(c.tagFormat, c.containerID)
+
+	if c.agg != nil {
+		c.agg.setGauge(c.prefix+bucket, tags, value)
+		return
+	}
+
+	if c.rate != 1 && randFloat() > c.rate {
+		return
+	}
+
+	c.sink.WriteMetric(c.prefix, bucket, value, "g", 1, tags)
 }
 
 // Timing sends a timing value to a bucket.
@@ -117,7 +206,7 @@ func (c *Client) Timing(bucket string, value interface{}) {
 	if c.skip() {
 		return
 	}
-	c.conn.metric(c.prefix, bucket, value, "ms", c.rate, c.tags.format(c.tagFormat))
+	c.sink.WriteMetric(c.prefix, bucket, value, "ms", c.rate, c.tags.format(c.tagFormat, c.containerID))
 }
 
 // Histogram sends an histogram value to a bucket.
@@ -128,7 +217,41 @@ func (c *Client) Histogram(bucket string, value interface{}, metricTags ...strin
 
 	mTags := newTags(metricTags...)
 	mTags.append(c.tags)
-	c.conn.metric(c.prefix, bucket, value, "h", c.rate, mTags.format(c.tagFormat))
+	c.sink.WriteMetric(c.prefix, bucket, value, "h", c.rate, mTags.format(c.tagFormat, c.containerID))
+}
+
+// Distribution sends a distribution value to a bucket. Distributions are a
+// Datadog extension to the StatsD protocol: they are akin to histograms but
+// the percentile aggregation happens (by default) on the Datadog agent
+// instead of client-side.
+//
+// If the ClientSideAggregation option was used, the value is instead kept in
+// an in-memory reservoir and only the aggregated statistics are sent to the
+// StatsD daemon, as gauges, at the end of each window. In that case,
+// Distribution is not subject to SampleRate: the reservoir's count/sum/min/
+// max are exact, and sampling on top of aggregating would only distort them
+// without reducing wire volume any further.
+func (c *Client) Distribution(bucket string, value interface{}, metricTags ...string) {
+	if c.muted {
+		return
+	}
+
+	mTags := newTags(metricTags...)
+	mTags.append(c.tags)
+	tags := mTags.format(c.tagFormat, c.containerID)
+
+	if c.distAgg != nil {
+		if f, ok := toFloat64(value); ok {
+			c.distAgg.add(c.prefix+bucket, tags, f)
+			return
+		}
+	}
+
+	if c.rate != 1 && randFloat() > c.rate {
+		return
+	}
+
+	c.sink.WriteMetric(c.prefix, bucket, value, "d", c.rate, tags)
 }
 
 // A Timing is an helper object that eases sending timing values.
@@ -153,11 +276,27 @@ func (t Timing) Duration() time.Duration {
 }
 
 // Unique sends the given value to a set bucket.
+//
+// When Aggregation is enabled, Unique is not subject to SampleRate: the
+// aggregator already dedupes each distinct value and flushes it once per
+// interval, and sampling on top of that would drop values from the set
+// instead of keeping it exact.
 func (c *Client) Unique(bucket string, value string) {
-	if c.skip() {
+	if c.muted {
+		return
+	}
+	tags := c.tags.format(c.tagFormat, c.containerID)
+
+	if c.agg != nil {
+		c.agg.addUnique(c.prefix+bucket, tags, value)
 		return
 	}
-	c.conn.unique(c.prefix, bucket, value, c.tags.format(c.tagFormat))
+
+	if c.rate != 1 && randFloat() > c.rate {
+		return
+	}
+
+	c.sink.WriteUnique(c.prefix, bucket, value, tags)
 }
 
 // Flush flushes the Client's buffer.
@@ -165,20 +304,29 @@ func (c *Client) Flush() {
 	if c.muted {
 		return
 	}
-	c.conn.mu.Lock()
-	c.conn.flush(0)
-	c.conn.mu.Unlock()
+	if c.agg != nil {
+		c.agg.flush()
+	}
+	c.sink.Flush()
 }
 
 // Close flushes the Client's buffer and releases the associated ressources. The
 // Client and all the cloned Clients must not be used afterward.
 func (c *Client) Close() {
+	// init starts these goroutines regardless of Mute, so they must be
+	// stopped regardless of it too, or a muted Client with Aggregation,
+	// ClientSideAggregation or Telemetry enabled would leak them forever.
+	if c.distAgg != nil {
+		c.distAgg.stop()
+	}
+	if c.agg != nil {
+		c.agg.stop()
+	}
+	if c.telemetry != nil {
+		c.telemetry.stop()
+	}
 	if c.muted {
 		return
 	}
-	c.conn.mu.Lock()
-	c.conn.flush(0)
-	c.conn.handleError(c.conn.w.Close())
-	c.conn.closed = true
-	c.conn.mu.Unlock()
+	_ = c.sink.Close()
 }
@@ -0,0 +1,146 @@
+package statsd
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Telemetry starts a goroutine that periodically reports the Client's own
+// health as StatsD metrics, sent through the same sink as every other
+// metric. Every name is placed under prefix; with prefix "statsd" they are:
+// statsd.client.metrics (tagged by type: count, gauge, timing, histogram,
+// distribution, set, event or service_check), statsd.client.events,
+// statsd.client.service_checks, statsd.client.bytes_sent,
+// statsd.client.bytes_dropped, statsd.client.packets_sent,
+// statsd.client.packets_dropped and, when Aggregation is enabled,
+// statsd.client.aggregated_context.
+//
+// Every metric is tagged with client:go, client_version:<Version> and
+// client_transport:<network>, using the Client's configured TagFormat. All
+// counters except aggregated_context are only tracked by NewNetSink, so they
+// report as zero for a Client built with NewWithSink and a different Sink.
+//
+// This option is ignored in Client.Clone(); clones share their parent's
+// telemetry reporter instead.
+func Telemetry(prefix string, interval time.Duration) Option {
+	return Option(func(c *config) {
+		c.Client.TelemetryPrefix = prefix
+		c.Client.TelemetryInterval = interval
+	})
+}
+
+// telemetryReporter periodically snapshots a conn's telemetry counters, if
+// the Client's sink is one, and writes them back through the sink as
+// gauges.
+type telemetryReporter struct {
+	c      *Client
+	conn   *conn // nil unless the Client's sink is a *conn (NewNetSink)
+	prefix string
+	base   *Tags
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newTelemetryReporter(c *Client, prefix string, interval time.Duration) *telemetryReporter {
+	conn, _ := c.sink.(*conn)
+	r := &telemetryReporter{
+		c:      c,
+		conn:   conn,
+		prefix: strings.TrimSuffix(prefix, ".") + ".",
+		base: newTags(
+			"client", "go",
+			"client_version", Version,
+			"client_transport", c.transport,
+		),
+		done: make(chan struct{}),
+	}
+	go r.loop(interval)
+	return r
+}
+
+func (r *telemetryReporter) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.report()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// stop shuts the reporter down. It is safe to call more than once.
+func (r *telemetryReporter) stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+}
+
+// tagsFor formats the reporter's base tags, plus a "type" tag when
+// metricType is non-empty.
+func (r *telemetryReporter) tagsFor(metricType string) string {
+	tags := r.base
+	if metricType != "" {
+		tags = tags.clone()
+		tags.append(newTags("type", metricType))
+	}
+	return tags.format(r.c.tagFormat, r.c.containerID)
+}
+
+// write emits one telemetry gauge line. It bypasses Sink.WriteMetric's
+// normal sample counting when the sink is a *conn, so that the telemetry
+// reporter's own output is never counted as a sample of the thing it is
+// reporting on (see tm, read from in report()).
+func (r *telemetryReporter) write(bucket string, value int64, tags string) {
+	if r.conn != nil {
+		r.conn.writeMetricLine(r.prefix, bucket, value, "g", 1, tags)
+		return
+	}
+	r.c.sink.WriteMetric(r.prefix, bucket, value, "g", 1, tags)
+}
+
+func (r *telemetryReporter) report() {
+	if r.c.muted {
+		return
+	}
+
+	tm := &telemetry{}
+	if r.conn != nil {
+		tm = &r.conn.tm
+	}
+
+	byType := []struct {
+		name string
+		n    *int64
+	}{
+		{"count", &tm.counts},
+		{"gauge", &tm.gauges},
+		{"timing", &tm.timings},
+		{"histogram", &tm.histograms},
+		{"distribution", &tm.distributions},
+		{"set", &tm.sets},
+		{"event", &tm.events},
+		{"service_check", &tm.serviceChecks},
+	}
+	for _, t := range byType {
+		r.write("client.metrics", atomic.LoadInt64(t.n), r.tagsFor(t.name))
+	}
+
+	tags := r.tagsFor("")
+	r.write("client.events", atomic.LoadInt64(&tm.events), tags)
+	r.write("client.service_checks", atomic.LoadInt64(&tm.serviceChecks), tags)
+	r.write("client.bytes_sent", atomic.LoadInt64(&tm.bytesSent), tags)
+	r.write("client.bytes_dropped", atomic.LoadInt64(&tm.bytesDropped), tags)
+	r.write("client.packets_sent", atomic.LoadInt64(&tm.packetsSent), tags)
+	r.write("client.packets_dropped", atomic.LoadInt64(&tm.packetsDropped), tags)
+
+	if r.c.agg != nil {
+		r.write("client.aggregated_context", int64(r.c.agg.contextCount()), tags)
+	}
+}
@@ -0,0 +1,75 @@
+package statsd
+
+import (
+	"testing"
+	"time"
+)
+
+// newMutedTelemetryClient returns a Client backed by a real, unconnected
+// *conn (so telemetryReporter.conn is populated and tm is tracked) without
+// ever dialing a socket.
+func newMutedTelemetryClient(t *testing.T, interval time.Duration) *Client {
+	t.Helper()
+	cn, err := newConn(ConnConfig{MaxPacketSize: 1440}, true)
+	if err != nil {
+		t.Fatalf("newConn() error = %v", err)
+	}
+	return NewWithSink(cn, Telemetry("dd", interval))
+}
+
+func TestTelemetryReportDoesNotCountItsOwnOutput(t *testing.T) {
+	c := newMutedTelemetryClient(t, time.Hour)
+	defer c.telemetry.stop()
+
+	c.Gauge("foo", 1)
+	if got := c.telemetry.conn.tm.gauges; got != 1 {
+		t.Fatalf("tm.gauges after one user Gauge = %d, want 1", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		c.telemetry.report()
+	}
+
+	if got := c.telemetry.conn.tm.gauges; got != 1 {
+		t.Errorf("tm.gauges after 3 report() calls = %d, want 1 (report's own gauge lines must not self-count)", got)
+	}
+}
+
+func TestTelemetryReportTagsEachMetricType(t *testing.T) {
+	sink := NewMemorySink()
+	c := NewWithSink(sink, Telemetry("dd", time.Hour), TagsFormat(Datadog))
+	defer c.Close()
+
+	c.telemetry.report()
+
+	var metricsLines int
+	for _, m := range sink.Metrics {
+		if m.Prefix == "dd." && m.Bucket == "client.metrics" {
+			metricsLines++
+		}
+	}
+	if metricsLines != 8 {
+		t.Errorf("got %d client.metrics lines, want 8 (count/gauge/timing/histogram/distribution/set/event/service_check)", metricsLines)
+	}
+}
+
+// TestTelemetryReportSkipsMutedClient guards against the telemetry reporter
+// writing straight into a muted conn's buffer: since a muted conn never
+// dials, flush() is a no-op and never resets the buffer, so any write would
+// accumulate forever for the life of the Client.
+func TestTelemetryReportSkipsMutedClient(t *testing.T) {
+	c, err := New(Mute(true), Telemetry("dd", time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer c.Close()
+
+	cn := c.sink.(*conn)
+	for i := 0; i < 3; i++ {
+		c.telemetry.report()
+	}
+
+	if got := cn.buf.Len(); got != 0 {
+		t.Errorf("conn buffer length after 3 report() calls on a muted Client = %d, want 0", got)
+	}
+}
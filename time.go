@@ -0,0 +1,6 @@
+package statsd
+
+import "time"
+
+// now returns the current time. It is a variable so tests can stub it out.
+var now = time.Now
@@ -0,0 +1,5 @@
+package statsd
+
+// Version is the current version of this package. It is reported as the
+// client_version tag on self-telemetry metrics; see Telemetry.
+const Version = "2.1.0"